@@ -1,15 +1,31 @@
 package main
 
 import (
+	"context"
+	"crypto/ecdsa"
 	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
 	"encoding/json"
 	"encoding/pem"
 	"errors"
 	"fmt"
+	"net/http"
 	"os"
 	"os/exec"
+	"os/signal"
+	"regexp"
+	"strings"
+	"sync"
+	"syscall"
 
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	gitssh "github.com/go-git/go-git/v5/plumbing/transport/ssh"
+	"github.com/kevinburke/ssh_config"
 	"golang.org/x/crypto/ssh"
+	"golang.org/x/term"
 
 	"github.com/urfave/cli/v2"
 )
@@ -18,14 +34,26 @@ type Keypair struct {
 	// Unique name of this key pair.
 	Id string
 
-	// Ed25519 public key in OpenSSH format.
+	// Public key in OpenSSH format.
 	PublicKey string
 
-	// Ed25519 private key in OpenSSH format.
+	// Private key in OpenSSH format.
 	PrivateKey string
 
 	// GitHub repository URL.
 	RepositoryURL string
+
+	// Algorithm used to generate this key pair.
+	Algorithm string
+
+	// Encrypted reports whether PrivateKey is passphrase-encrypted and must be
+	// decrypted before use.
+	Encrypted bool
+
+	// RemoteKeyID is the GitHub deploy key ID returned by the "upload"
+	// command, used by "revoke" to delete it. Zero means PublicKey has not
+	// been uploaded yet.
+	RemoteKeyID int64
 }
 
 type Keyring map[string]Keypair
@@ -62,39 +90,86 @@ func (kr Keyring) Save(file string) error {
 
 	// Write the encoded JSON to the key ring file. If doesn't exists, create it
 	// with strict permissions.
-	if err := os.WriteFile(file, data, 0666); err != nil {
+	if err := os.WriteFile(file, data, 0600); err != nil {
 		return fmt.Errorf("failed to save the key ring: %w", err)
 	}
 
 	return nil
 }
 
-// GenerateKeys returns an Ed25519 SSH key pair (public and private keys).
+// Algorithm identifies the key algorithm used to generate a key pair.
+type Algorithm string
+
+const (
+	AlgorithmEd25519 Algorithm = "ed25519"
+	AlgorithmECDSA   Algorithm = "ecdsa"
+	AlgorithmRSA4096 Algorithm = "rsa4096"
+)
+
+// ParseAlgorithm converts a string, such as the one passed to the
+// "--algorithm" flag, to an Algorithm. It returns an error if the string does
+// not match any known algorithm.
+func ParseAlgorithm(s string) (Algorithm, error) {
+	switch Algorithm(s) {
+	case AlgorithmEd25519, AlgorithmECDSA, AlgorithmRSA4096:
+		return Algorithm(s), nil
+	default:
+		return "", fmt.Errorf("unrecognized algorithm %q", s)
+	}
+}
+
+// GenerateKeys returns a SSH key pair (public and private keys) generated
+// with the given algorithm.
+//
+// If passphrase is not empty, the private key is encrypted with it and must
+// be decrypted (see decryptPrivateKey) before use.
 //
 // The keys are encoded as string in OpenSSH format.
-func GenerateKeys() (string, string, error) {
-	// Generate a new ed25519 key pair.
-	edPublic, edPrivate, err := ed25519.GenerateKey(nil)
-	if err != nil {
-		fmt.Errorf("Failed to generate ed25519 key pair: %w", err)
-		return "", "", err
+func GenerateKeys(algorithm Algorithm, passphrase []byte) (string, string, error) {
+	// Generate a new raw key pair with the requested algorithm.
+	var public, private any
+	switch algorithm {
+	case AlgorithmEd25519:
+		edPublic, edPrivate, err := ed25519.GenerateKey(rand.Reader)
+		if err != nil {
+			return "", "", fmt.Errorf("failed to generate ed25519 key pair: %w", err)
+		}
+		public, private = edPublic, edPrivate
+	case AlgorithmECDSA:
+		ecPrivate, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+		if err != nil {
+			return "", "", fmt.Errorf("failed to generate ecdsa key pair: %w", err)
+		}
+		public, private = &ecPrivate.PublicKey, ecPrivate
+	case AlgorithmRSA4096:
+		rsaPrivate, err := rsa.GenerateKey(rand.Reader, 4096)
+		if err != nil {
+			return "", "", fmt.Errorf("failed to generate rsa4096 key pair: %w", err)
+		}
+		public, private = &rsaPrivate.PublicKey, rsaPrivate
+	default:
+		return "", "", fmt.Errorf("unrecognized algorithm %q", algorithm)
 	}
 
 	// Create a new SSH public key from the raw key.
-	sshPublic, err := ssh.NewPublicKey(edPublic)
+	sshPublic, err := ssh.NewPublicKey(public)
 	if err != nil {
-		fmt.Errorf("Failed to create SSH public key: %w", err)
-		return "", "", err
+		return "", "", fmt.Errorf("failed to create SSH public key: %w", err)
 	}
 
 	// Serialize the SSH public key to OpenSSH format.
 	strPublic := string(ssh.MarshalAuthorizedKey(sshPublic))
 
 	// Serialize the SSH private key to OpenSSH format. It returns a PEM block.
-	pemPrivate, err := ssh.MarshalPrivateKey(edPrivate, "")
+	// If a passphrase was requested, the block is encrypted with it.
+	var pemPrivate *pem.Block
+	if len(passphrase) > 0 {
+		pemPrivate, err = ssh.MarshalPrivateKeyWithPassphrase(private, "", passphrase)
+	} else {
+		pemPrivate, err = ssh.MarshalPrivateKey(private, "")
+	}
 	if err != nil {
-		fmt.Errorf("Failed to create PEM block for private key: %w", err)
-		return "", "", err
+		return "", "", fmt.Errorf("failed to create PEM block for private key: %w", err)
 	}
 
 	// Convert the PEM block to string.
@@ -103,11 +178,178 @@ func GenerateKeys() (string, string, error) {
 	return strPublic, strPrivate, nil
 }
 
+// CloneOptions collects the optional knobs "clone" can tweak on top of the
+// repository URL, key pair and destination folder.
+type CloneOptions struct {
+	// Depth limits the clone to the given number of commits. Zero means a
+	// full clone.
+	Depth int
+
+	// SingleBranch clones only the tip of a single branch.
+	SingleBranch bool
+
+	// ReferenceName is the branch to check out. Empty means the remote's
+	// default branch.
+	ReferenceName string
+}
+
+// scpLikeURLPattern matches the SCP-like "user@host:path" form.
+var scpLikeURLPattern = regexp.MustCompile(`^([^@]+)@([^:]+):(.+)$`)
+
+// sshSchemeURLPattern matches the "ssh://[user@]host[:port]/path" form.
+var sshSchemeURLPattern = regexp.MustCompile(`^ssh://(?:([^@]+)@)?([^:/]+)(?::(\d+))?/(.+)$`)
+
+// sshURLParts splits a SSH repository URL, in either the SCP-like or
+// "ssh://" form, into its user, host, port and path components. ok is false
+// if repositoryURL is not a recognized SSH URL (for instance a "https://"
+// one).
+func sshURLParts(repositoryURL string) (user, host, port, path string, ok bool) {
+	// The "ssh://" form must be tried first: it also contains an "@" and a
+	// ":", so the SCP-like pattern would otherwise match it first and mangle
+	// the scheme, port and path.
+	if m := sshSchemeURLPattern.FindStringSubmatch(repositoryURL); m != nil {
+		return m[1], m[2], m[3], m[4], true
+	}
+	if m := scpLikeURLPattern.FindStringSubmatch(repositoryURL); m != nil {
+		return m[1], m[2], "", m[3], true
+	}
+	return "", "", "", "", false
+}
+
+// sshConfigHost is the subset of a Host block of the user's ~/.ssh/config
+// that we care about.
+type sshConfigHost struct {
+	HostName string
+	User     string
+	Port     string
+}
+
+var (
+	sshConfigHostCacheMu sync.Mutex
+	sshConfigHostCache   = map[string]sshConfigHost{}
+)
+
+// resolveSSHConfigHost looks up alias in the user's ~/.ssh/config (via
+// kevinburke/ssh_config, which also consults the system-wide config and
+// applies OpenSSH's defaults). Results are cached per alias, so each alias is
+// looked up at most once per process.
+func resolveSSHConfigHost(alias string) sshConfigHost {
+	sshConfigHostCacheMu.Lock()
+	defer sshConfigHostCacheMu.Unlock()
+
+	if host, ok := sshConfigHostCache[alias]; ok {
+		return host
+	}
+
+	host := sshConfigHost{
+		HostName: ssh_config.Get(alias, "HostName"),
+		User:     ssh_config.Get(alias, "User"),
+		Port:     ssh_config.Get(alias, "Port"),
+	}
+
+	sshConfigHostCache[alias] = host
+	return host
+}
+
+// rewriteRepositoryURL resolves repositoryURL's host through the user's
+// ~/.ssh/config and rewrites the URL to use the resolved HostName, User and
+// Port. This is necessary because, unlike the "git" binary, go-git does not
+// consult ~/.ssh/config itself, so a host alias like "github-work" would
+// otherwise fail to resolve. A port given in the URL itself takes precedence
+// over the configured one. If repositoryURL is not an SSH URL, it is returned
+// unchanged; an SSH URL is always rebuilt, in the SCP-like form unless it
+// needs a port other than 22, with the user "git" if neither the URL nor
+// ~/.ssh/config names one.
+func rewriteRepositoryURL(repositoryURL string) string {
+	user, host, port, path, ok := sshURLParts(repositoryURL)
+	if !ok {
+		return repositoryURL
+	}
+
+	resolved := resolveSSHConfigHost(host)
+	hostName := host
+	if resolved.HostName != "" {
+		hostName = resolved.HostName
+	}
+	if resolved.User != "" {
+		user = resolved.User
+	}
+	if port == "" {
+		port = resolved.Port
+	}
+	if user == "" {
+		user = "git"
+	}
+
+	if port != "" && port != "22" {
+		return fmt.Sprintf("ssh://%s@%s:%s/%s", user, hostName, port, path)
+	}
+	return fmt.Sprintf("%s@%s:%s", user, hostName, path)
+}
+
+// sshAuth builds an in-memory SSH public key auth method from a private key
+// in OpenSSH format, so it never has to touch disk.
+func sshAuth(privateKey string, passphrase []byte) (*gitssh.PublicKeys, error) {
+	auth, err := gitssh.NewPublicKeys("git", []byte(privateKey), string(passphrase))
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse private key: %w", err)
+	}
+	return auth, nil
+}
+
+// decryptPrivateKey decrypts a passphrase-encrypted private key in OpenSSH
+// format and re-encodes it, unencrypted, in the same format.
+func decryptPrivateKey(privateKey string, passphrase []byte) (string, error) {
+	raw, err := ssh.ParseRawPrivateKeyWithPassphrase([]byte(privateKey), passphrase)
+	if err != nil {
+		return "", fmt.Errorf("failed to decrypt private key: %w", err)
+	}
+
+	// Ed25519 keys are parsed as a pointer, which MarshalPrivateKey does not
+	// accept.
+	if k, ok := raw.(*ed25519.PrivateKey); ok {
+		raw = *k
+	}
+
+	pemPrivate, err := ssh.MarshalPrivateKey(raw, "")
+	if err != nil {
+		return "", fmt.Errorf("failed to create PEM block for private key: %w", err)
+	}
+
+	return string(pem.EncodeToMemory(pemPrivate)), nil
+}
+
+// resolvePassphrase returns the passphrase to use for a key pair, checking,
+// in order, the "--passphrase" flag and the GDKM_PASSPHRASE environment
+// variable. If neither is set and prompt is true, it falls back to an
+// interactive prompt via term.ReadPassword when stdin is a terminal. It
+// returns nil if no passphrase could be obtained.
+func resolvePassphrase(ctx *cli.Context, prompt bool) ([]byte, error) {
+	if p := ctx.String("passphrase"); p != "" {
+		return []byte(p), nil
+	}
+	if p := os.Getenv("GDKM_PASSPHRASE"); p != "" {
+		return []byte(p), nil
+	}
+	if !prompt || !term.IsTerminal(int(os.Stdin.Fd())) {
+		return nil, nil
+	}
+
+	fmt.Print("Passphrase: ")
+	passphrase, err := term.ReadPassword(int(os.Stdin.Fd()))
+	fmt.Println()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read passphrase: %w", err)
+	}
+	return passphrase, nil
+}
+
 // CloneRepository clones the specified Git repository over SSH using the
 // specified SSH key (in OpenSSH format) to the specified destination folder.
-func CloneRepository(repositoryURL, privateKey, dest string) error {
-	file := "key"
-
+//
+// The private key never touches disk: go-git is fed an in-memory public key
+// auth method built from it.
+func CloneRepository(repositoryURL, privateKey string, passphrase []byte, dest string, opts CloneOptions) error {
 	// dest directory must be empty or not existent.
 	entries, err := os.ReadDir(dest)
 	if err != nil {
@@ -118,34 +360,147 @@ func CloneRepository(repositoryURL, privateKey, dest string) error {
 		os.Exit(1)
 	}
 
-	// The privateKey must be temporarily saved to disk to be used by the SSH
-	// client.
-	//
-	// The permission bit must be restricted, otherwise the SSH client won't be
-	// able to read the key file.
-	if err := os.WriteFile(file, []byte(privateKey), 0600); err != nil {
-		return fmt.Errorf("failed to create temporary file for private key: %w", err)
+	auth, err := sshAuth(privateKey, passphrase)
+	if err != nil {
+		return err
+	}
+
+	cloneOpts := &git.CloneOptions{
+		URL:          rewriteRepositoryURL(repositoryURL),
+		Auth:         auth,
+		Depth:        opts.Depth,
+		SingleBranch: opts.SingleBranch,
 	}
-	defer func() { // Remember to delete the file at exit.
-		if err := os.Remove(file); err != nil {
-			fmt.Errorf("failed to delete remporary file for private key: %w", err)
+	if opts.ReferenceName != "" {
+		cloneOpts.ReferenceName = plumbing.NewBranchReferenceName(opts.ReferenceName)
+	}
+
+	if _, err := git.PlainCloneContext(context.Background(), dest, false, cloneOpts); err != nil {
+		return fmt.Errorf("failed to clone repository: %w", err)
+	}
+
+	return nil
+}
+
+// sshRepoPattern matches the SCP-like "git@host:owner/repo.git" form.
+var sshRepoPattern = regexp.MustCompile(`^[^@]+@[^:]+:([^/]+)/(.+)$`)
+
+// ParseGitHubRepo extracts the "owner" and "repo" path segments from a GitHub
+// repository URL, accepting both the SSH ("git@github.com:owner/repo.git")
+// and HTTPS ("https://github.com/owner/repo") forms.
+func ParseGitHubRepo(repositoryURL string) (owner, repo string, err error) {
+	if m := sshRepoPattern.FindStringSubmatch(repositoryURL); m != nil {
+		owner, repo = m[1], m[2]
+	} else if strings.Contains(repositoryURL, "://") {
+		path := strings.TrimPrefix(repositoryURL, "https://")
+		path = strings.TrimPrefix(path, "http://")
+		// path is now "host/owner/repo[/...]"; drop the host.
+		parts := strings.SplitN(path, "/", 3)
+		if len(parts) < 3 {
+			return "", "", fmt.Errorf("failed to parse repository URL %q", repositoryURL)
 		}
-	}()
+		owner, repo = parts[1], parts[2]
+		if idx := strings.Index(repo, "/"); idx >= 0 {
+			repo = repo[:idx]
+		}
+	} else {
+		return "", "", fmt.Errorf("failed to parse repository URL %q", repositoryURL)
+	}
+
+	repo = strings.TrimSuffix(repo, ".git")
+	if owner == "" || repo == "" {
+		return "", "", fmt.Errorf("failed to parse repository URL %q", repositoryURL)
+	}
+
+	return owner, repo, nil
+}
+
+// githubRequest sends an authenticated request to the GitHub REST API and
+// decodes a JSON response into out, if out is not nil. The caller is
+// responsible for closing the returned response's body.
+func githubRequest(method, url, token string, body, out any) (*http.Response, error) {
+	var reader *strings.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return nil, fmt.Errorf("failed to encode request body: %w", err)
+		}
+		reader = strings.NewReader(string(data))
+	} else {
+		reader = strings.NewReader("")
+	}
+
+	req, err := http.NewRequest(method, url, reader)
+	if err != nil {
+		return nil, fmt.Errorf("failed to build request: %w", err)
+	}
+	req.Header.Set("Accept", "application/vnd.github+json")
+	req.Header.Set("Authorization", "Bearer "+token)
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
 
-	// The command to execute.
-	//
-	// An complete example is:
-	// 		git clone -c "core.sshCommand=ssh -i TMP_FILE -o IdentitiesOnly=yes"
-	//			git@github.com:username/private-repo.git
-	//
-	// The core idea is to instruct the SSH client, called by Git, to use our
-	// private key and not ask the SSH agent for other keys.
-	cmd := exec.Command("git", "clone",
-		"-c", fmt.Sprintf("core.sshCommand=ssh -i %s -o IdentitiesOnly=yes", file),
-		repositoryURL, dest)
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to call GitHub API: %w", err)
+	}
 
-	if err := cmd.Run(); err != nil {
-		return fmt.Errorf("failed to run git clone: %w", err)
+	if out != nil {
+		if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+			return resp, fmt.Errorf("failed to decode GitHub API response: %w", err)
+		}
+	}
+
+	return resp, nil
+}
+
+// UploadDeployKey installs publicKey as a deploy key on the given GitHub
+// repository and returns its remote key ID.
+func UploadDeployKey(token, owner, repo, title, publicKey string, writable bool) (int64, error) {
+	url := fmt.Sprintf("https://api.github.com/repos/%s/%s/keys", owner, repo)
+	reqBody := map[string]any{
+		"title":     title,
+		"key":       publicKey,
+		"read_only": !writable,
+	}
+
+	var respBody struct {
+		ID      int64  `json:"id"`
+		Message string `json:"message"`
+	}
+	resp, err := githubRequest(http.MethodPost, url, token, reqBody, &respBody)
+	if resp != nil {
+		defer resp.Body.Close()
+	}
+	if err != nil {
+		return 0, err
+	}
+
+	switch resp.StatusCode {
+	case http.StatusCreated:
+		return respBody.ID, nil
+	case http.StatusUnprocessableEntity:
+		return 0, fmt.Errorf("GitHub rejected the key: %s", respBody.Message)
+	default:
+		return 0, fmt.Errorf("GitHub API returned %s: %s", resp.Status, respBody.Message)
+	}
+}
+
+// RevokeDeployKey deletes a previously uploaded deploy key from the given
+// GitHub repository.
+func RevokeDeployKey(token, owner, repo string, keyID int64) error {
+	url := fmt.Sprintf("https://api.github.com/repos/%s/%s/keys/%d", owner, repo, keyID)
+
+	resp, err := githubRequest(http.MethodDelete, url, token, nil, nil)
+	if resp != nil {
+		defer resp.Body.Close()
+	}
+	if err != nil {
+		return err
+	}
+
+	if resp.StatusCode != http.StatusNoContent {
+		return fmt.Errorf("GitHub API returned %s", resp.Status)
 	}
 
 	return nil
@@ -180,8 +535,22 @@ func CliGenerateKeypair(ctx *cli.Context) error {
 		os.Exit(1)
 	}
 
+	// Parse the requested key algorithm.
+	algorithm, err := ParseAlgorithm(ctx.String("algorithm"))
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%s\n", err)
+		os.Exit(1)
+	}
+
+	// Encryption is opt-in: only "--passphrase"/GDKM_PASSPHRASE turn it on, we
+	// never prompt for one that was not asked for.
+	passphrase, err := resolvePassphrase(ctx, false)
+	if err != nil {
+		return err
+	}
+
 	// Generate a SSH key pair.
-	public, private, err := GenerateKeys()
+	public, private, err := GenerateKeys(algorithm, passphrase)
 	if err != nil {
 		return fmt.Errorf("failed to generate key pair: %w", err)
 	}
@@ -191,6 +560,8 @@ func CliGenerateKeypair(ctx *cli.Context) error {
 		PublicKey:     public,
 		PrivateKey:    private,
 		RepositoryURL: repoURL,
+		Algorithm:     string(algorithm),
+		Encrypted:     len(passphrase) > 0,
 	}
 
 	// The "generate" command modifies the key ring. It must be saved to disk.
@@ -236,13 +607,252 @@ func CliCloneRepository(ctx *cli.Context) error {
 		os.Exit(1)
 	}
 
-	if err := CloneRepository(keypair.RepositoryURL, keypair.PrivateKey, id); err != nil {
+	// A passphrase is mandatory if the stored private key is encrypted.
+	var passphrase []byte
+	if keypair.Encrypted {
+		passphrase, err = resolvePassphrase(ctx, true)
+		if err != nil {
+			return err
+		}
+		if len(passphrase) == 0 {
+			fmt.Fprintf(os.Stderr, "Key pair %q is passphrase-encrypted, pass --passphrase or set GDKM_PASSPHRASE\n", id)
+			os.Exit(1)
+		}
+	}
+
+	opts := CloneOptions{
+		Depth:         ctx.Int("depth"),
+		SingleBranch:  ctx.Bool("single-branch"),
+		ReferenceName: ctx.String("branch"),
+	}
+
+	if err := CloneRepository(keypair.RepositoryURL, keypair.PrivateKey, passphrase, id, opts); err != nil {
 		return fmt.Errorf("failed to clone repository associated with id %q: %w", id, err)
 	}
 
 	return nil
 }
 
+// CliPullRepository implements the "pull" command.
+//
+// It pulls updates for the working tree previously cloned with "clone" into
+// the "./id" folder, using the same in-memory SSH auth as CloneRepository.
+func CliPullRepository(ctx *cli.Context) error {
+	keyringFile := ctx.String("keyring")
+
+	// Load the key ring from file.
+	keyring, err := Load(keyringFile)
+	if err != nil {
+		return fmt.Errorf("failed to load key ring: %w", err)
+	}
+
+	// Extract ID argument from command line.
+	args := ctx.Args()
+	if args.Len() != 1 {
+		fmt.Fprintln(os.Stderr, "Missing mandatory [id] argument")
+		os.Exit(1)
+	}
+	id := args.Get(0)
+
+	// Get the selected key pair.
+	keypair, exist := keyring[id]
+	if !exist {
+		fmt.Fprintf(os.Stderr, "Key pair %q does not exist\n", id)
+		os.Exit(1)
+	}
+
+	if ctx.Bool("rebase") {
+		// go-git's Worktree.Pull only performs a fast-forward merge; it has no
+		// rebase mode to fall back to.
+		fmt.Fprintln(os.Stderr, "--rebase is not supported: go-git only supports fast-forward pulls")
+		os.Exit(1)
+	}
+	if !ctx.Bool("ff-only") {
+		// Same limitation as above: go-git cannot create merge commits, so a
+		// non-fast-forward pull cannot be honored either.
+		fmt.Fprintln(os.Stderr, "only fast-forward pulls are supported, pass --ff-only")
+		os.Exit(1)
+	}
+
+	// A passphrase is mandatory if the stored private key is encrypted.
+	var passphrase []byte
+	if keypair.Encrypted {
+		passphrase, err = resolvePassphrase(ctx, true)
+		if err != nil {
+			return err
+		}
+		if len(passphrase) == 0 {
+			fmt.Fprintf(os.Stderr, "Key pair %q is passphrase-encrypted, pass --passphrase or set GDKM_PASSPHRASE\n", id)
+			os.Exit(1)
+		}
+	}
+
+	repo, err := git.PlainOpen(id)
+	if err != nil {
+		return fmt.Errorf("failed to open working tree %q: %w", id, err)
+	}
+
+	worktree, err := repo.Worktree()
+	if err != nil {
+		return fmt.Errorf("failed to open working tree %q: %w", id, err)
+	}
+
+	if !ctx.Bool("force") {
+		status, err := worktree.Status()
+		if err != nil {
+			return fmt.Errorf("failed to check working tree %q status: %w", id, err)
+		}
+		if !status.IsClean() {
+			fmt.Fprintf(os.Stderr, "Working tree %q has uncommitted changes, pass --force to pull anyway\n", id)
+			os.Exit(1)
+		}
+	}
+
+	auth, err := sshAuth(keypair.PrivateKey, passphrase)
+	if err != nil {
+		return err
+	}
+
+	// PullOptions.Force controls something unrelated and more dangerous than
+	// our "--force": it allows a non-fast-forward ref update, which can
+	// silently discard diverged local commits. We never want that — this
+	// command already hard-refuses non-fast-forward pulls above — so it is
+	// always left false regardless of "--force".
+	pullOpts := &git.PullOptions{Auth: auth}
+	if err := worktree.PullContext(context.Background(), pullOpts); err != nil {
+		if errors.Is(err, git.NoErrAlreadyUpToDate) {
+			fmt.Println("Already up to date")
+			return nil
+		}
+		return fmt.Errorf("failed to pull repository associated with id %q: %w", id, err)
+	}
+
+	return nil
+}
+
+// CliGitSSH implements the hidden "gitssh" command.
+//
+// It behaves like ssh, transparently substituting the private key stored in
+// the keyring for the given id. This lets external tools that shell out to
+// ssh (git submodules, LFS, Terraform, ...) use a keyring key via
+// GIT_SSH_COMMAND="gdkm gitssh <id>".
+//
+// The decrypted private key is written to a 0600 temporary file, passed to
+// ssh as "-i", and removed once ssh exits or is interrupted.
+func CliGitSSH(ctx *cli.Context) error {
+	keyringFile := ctx.String("keyring")
+
+	// Load the key ring from file.
+	keyring, err := Load(keyringFile)
+	if err != nil {
+		return fmt.Errorf("failed to load key ring: %w", err)
+	}
+
+	// Extract arguments from command line: the id, then the arguments to pass
+	// through to ssh, optionally separated by a literal "--".
+	args := ctx.Args()
+	if args.Len() < 1 {
+		fmt.Fprintln(os.Stderr, "Missing mandatory [id] argument")
+		os.Exit(1)
+	}
+	id := args.First()
+	sshArgs := args.Tail()
+	if len(sshArgs) > 0 && sshArgs[0] == "--" {
+		sshArgs = sshArgs[1:]
+	}
+
+	// Get the selected key pair.
+	keypair, exist := keyring[id]
+	if !exist {
+		fmt.Fprintf(os.Stderr, "Key pair %q does not exist\n", id)
+		os.Exit(1)
+	}
+
+	privateKey := keypair.PrivateKey
+	if keypair.Encrypted {
+		passphrase, err := resolvePassphrase(ctx, true)
+		if err != nil {
+			return err
+		}
+		if len(passphrase) == 0 {
+			fmt.Fprintf(os.Stderr, "Key pair %q is passphrase-encrypted, set GDKM_PASSPHRASE\n", id)
+			os.Exit(1)
+		}
+		privateKey, err = decryptPrivateKey(keypair.PrivateKey, passphrase)
+		if err != nil {
+			return fmt.Errorf("failed to decrypt private key: %w", err)
+		}
+	}
+
+	// The private key must be temporarily saved to disk for ssh to read it,
+	// with a restricted permission bit.
+	tmp, err := os.CreateTemp("", "gdkm-*")
+	if err != nil {
+		return fmt.Errorf("failed to create temporary file for private key: %w", err)
+	}
+	tmpPath := tmp.Name()
+	defer os.Remove(tmpPath)
+
+	if err := tmp.Chmod(0600); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to restrict permissions of temporary file: %w", err)
+	}
+	if _, err := tmp.WriteString(privateKey); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to write temporary file for private key: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to close temporary file for private key: %w", err)
+	}
+
+	sshPath, err := exec.LookPath("ssh")
+	if err != nil {
+		return fmt.Errorf("failed to find ssh binary: %w", err)
+	}
+
+	// Run ssh as a child process, not via syscall.Exec: exec() replaces this
+	// process image (and all its goroutines) in place, so nothing would be
+	// left alive afterwards to unlink the temporary key file, which is
+	// exactly the case (ssh getting interrupted) the cleanup is for. Running
+	// it as a child lets the deferred os.Remove above fire once it exits,
+	// and signals are forwarded explicitly so SIGINT/SIGTERM still reach it.
+	cmdArgs := append([]string{"-i", tmpPath, "-o", "IdentitiesOnly=yes"}, sshArgs...)
+	cmd := exec.Command(sshPath, cmdArgs...)
+	cmd.Stdin = os.Stdin
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+
+	sigCh := make(chan os.Signal, 1)
+	signal.Notify(sigCh, syscall.SIGINT, syscall.SIGTERM)
+	defer signal.Stop(sigCh)
+
+	if err := cmd.Start(); err != nil {
+		return fmt.Errorf("failed to start ssh: %w", err)
+	}
+
+	go func() {
+		for sig := range sigCh {
+			cmd.Process.Signal(sig)
+		}
+	}()
+
+	if err := cmd.Wait(); err != nil {
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) {
+			// Return rather than os.Exit here: os.Exit would skip the
+			// deferred os.Remove(tmpPath) and signal.Stop above, leaving the
+			// decrypted private key behind on disk on every non-zero ssh
+			// exit (auth failure, unreachable host, Ctrl-C...). Returning a
+			// cli.ExitCoder lets the deferred cleanup run first; app.Run
+			// exits the process with this code once CliGitSSH has returned.
+			return cli.Exit("", exitErr.ExitCode())
+		}
+		return fmt.Errorf("failed to run ssh: %w", err)
+	}
+
+	return nil
+}
+
 // CliGetField prints the specified field of a key pair via its ID. If not ID is
 // provided, it prints all saved IDs.
 func CliGetField(ctx *cli.Context) error {
@@ -289,7 +899,22 @@ func CliGetField(ctx *cli.Context) error {
 	case "PublicKey":
 		fmt.Print(keypair.PublicKey)
 	case "PrivateKey":
-		fmt.Print(keypair.PrivateKey)
+		privateKey := keypair.PrivateKey
+		if keypair.Encrypted {
+			passphrase, err := resolvePassphrase(ctx, true)
+			if err != nil {
+				return err
+			}
+			if len(passphrase) == 0 {
+				fmt.Fprintf(os.Stderr, "Key pair %q is passphrase-encrypted, pass --passphrase or set GDKM_PASSPHRASE\n", id)
+				os.Exit(1)
+			}
+			privateKey, err = decryptPrivateKey(keypair.PrivateKey, passphrase)
+			if err != nil {
+				return fmt.Errorf("failed to decrypt private key: %w", err)
+			}
+		}
+		fmt.Print(privateKey)
 	case "RepositoryURL":
 		fmt.Println(keypair.RepositoryURL)
 	default:
@@ -300,6 +925,134 @@ func CliGetField(ctx *cli.Context) error {
 	return nil
 }
 
+// resolveGitHubToken returns the token to authenticate to the GitHub API,
+// checking the "--token" flag and then the GITHUB_TOKEN environment
+// variable.
+func resolveGitHubToken(ctx *cli.Context) (string, error) {
+	if t := ctx.String("token"); t != "" {
+		return t, nil
+	}
+	if t := os.Getenv("GITHUB_TOKEN"); t != "" {
+		return t, nil
+	}
+	return "", errors.New("missing GitHub token: pass --token or set GITHUB_TOKEN")
+}
+
+// CliUploadKey implements the "upload" command.
+//
+// It installs the public key of the given key pair as a deploy key on its
+// GitHub repository and saves the returned remote key ID to the key ring.
+func CliUploadKey(ctx *cli.Context) error {
+	keyringFile := ctx.String("keyring")
+
+	// Load the key ring from file.
+	keyring, err := Load(keyringFile)
+	if err != nil {
+		return fmt.Errorf("failed to load key ring: %w", err)
+	}
+
+	// Extract ID argument from command line.
+	args := ctx.Args()
+	if args.Len() != 1 {
+		fmt.Fprintln(os.Stderr, "Missing mandatory [id] argument")
+		os.Exit(1)
+	}
+	id := args.Get(0)
+
+	// Get the selected key pair.
+	keypair, exist := keyring[id]
+	if !exist {
+		fmt.Fprintf(os.Stderr, "Key pair %q does not exist\n", id)
+		os.Exit(1)
+	}
+
+	token, err := resolveGitHubToken(ctx)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+
+	owner, repo, err := ParseGitHubRepo(keypair.RepositoryURL)
+	if err != nil {
+		return fmt.Errorf("failed to parse repository URL of key pair %q: %w", id, err)
+	}
+
+	keyID, err := UploadDeployKey(token, owner, repo, id, keypair.PublicKey, ctx.Bool("write"))
+	if err != nil {
+		return fmt.Errorf("failed to upload deploy key for %q: %w", id, err)
+	}
+
+	keypair.RemoteKeyID = keyID
+	keyring[id] = keypair
+
+	// The "upload" command modifies the key ring. It must be saved to disk.
+	if err := keyring.Save(keyringFile); err != nil {
+		return fmt.Errorf("failed to save key ring: %w", err)
+	}
+
+	fmt.Printf("Deploy key %q uploaded to %s/%s with id %d\n", id, owner, repo, keyID)
+
+	return nil
+}
+
+// CliRevokeKey implements the "revoke" command.
+//
+// It deletes the deploy key previously uploaded via "upload" for the given
+// key pair from its GitHub repository.
+func CliRevokeKey(ctx *cli.Context) error {
+	keyringFile := ctx.String("keyring")
+
+	// Load the key ring from file.
+	keyring, err := Load(keyringFile)
+	if err != nil {
+		return fmt.Errorf("failed to load key ring: %w", err)
+	}
+
+	// Extract ID argument from command line.
+	args := ctx.Args()
+	if args.Len() != 1 {
+		fmt.Fprintln(os.Stderr, "Missing mandatory [id] argument")
+		os.Exit(1)
+	}
+	id := args.Get(0)
+
+	// Get the selected key pair.
+	keypair, exist := keyring[id]
+	if !exist {
+		fmt.Fprintf(os.Stderr, "Key pair %q does not exist\n", id)
+		os.Exit(1)
+	}
+	if keypair.RemoteKeyID == 0 {
+		fmt.Fprintf(os.Stderr, "Key pair %q has no uploaded deploy key\n", id)
+		os.Exit(1)
+	}
+
+	token, err := resolveGitHubToken(ctx)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+
+	owner, repo, err := ParseGitHubRepo(keypair.RepositoryURL)
+	if err != nil {
+		return fmt.Errorf("failed to parse repository URL of key pair %q: %w", id, err)
+	}
+
+	if err := RevokeDeployKey(token, owner, repo, keypair.RemoteKeyID); err != nil {
+		return fmt.Errorf("failed to revoke deploy key for %q: %w", id, err)
+	}
+
+	keypair.RemoteKeyID = 0
+	keyring[id] = keypair
+
+	// The "revoke" command modifies the key ring. It must be saved to disk.
+	if err := keyring.Save(keyringFile); err != nil {
+		return fmt.Errorf("failed to save key ring: %w", err)
+	}
+
+	return nil
+}
+
 func main() {
 	// Create and configure the application.
 	app := cli.NewApp()
@@ -320,13 +1073,29 @@ func main() {
 
 	app.Flags = []cli.Flag{fileFlag}
 
+	// "--passphrase" option, shared by the commands that encrypt or decrypt a
+	// private key. It can also be set via the GDKM_PASSPHRASE environment
+	// variable.
+	passphraseFlag := &cli.StringFlag{
+		Name:  "passphrase",
+		Usage: "Passphrase to encrypt or decrypt the private key (or set GDKM_PASSPHRASE)",
+	}
+
 	// "generate" command.
 	genCommand := &cli.Command{
 		Name:      "generate",
 		Usage:     "Print the public key of a new SSH key pair in the key ring",
 		Args:      true,
 		ArgsUsage: " [id] [Repository URL]",
-		Action:    CliGenerateKeypair,
+		Flags: []cli.Flag{
+			&cli.StringFlag{
+				Name:  "algorithm",
+				Value: string(AlgorithmEd25519),
+				Usage: "Key algorithm to use: ed25519, ecdsa or rsa4096",
+			},
+			passphraseFlag,
+		},
+		Action: CliGenerateKeypair,
 	}
 
 	// "get" command.
@@ -335,6 +1104,7 @@ func main() {
 		Usage:     "Get a single field of the key ring. If id is not specified, return all ids.",
 		Args:      true,
 		ArgsUsage: " [id] [PublicKey|PrivateKey|RepositoryURL]",
+		Flags:     []cli.Flag{passphraseFlag},
 		Action:    CliGetField,
 	}
 
@@ -344,12 +1114,94 @@ func main() {
 		Usage:     "Clone the repository associated with the given key pair",
 		Args:      true,
 		ArgsUsage: " [id]",
-		Action:    CliCloneRepository,
+		Flags: []cli.Flag{
+			&cli.IntFlag{
+				Name:  "depth",
+				Usage: "Create a shallow clone with a history truncated to the given number of commits",
+			},
+			&cli.BoolFlag{
+				Name:  "single-branch",
+				Usage: "Clone only the tip of a single branch",
+			},
+			&cli.StringFlag{
+				Name:  "branch",
+				Usage: "Branch to check out instead of the remote's default branch",
+			},
+			passphraseFlag,
+		},
+		Action: CliCloneRepository,
+	}
+
+	// "--token" option, shared by the commands that call the GitHub API.
+	tokenFlag := &cli.StringFlag{
+		Name:  "token",
+		Usage: "GitHub API token (or set GITHUB_TOKEN)",
+	}
+
+	// "upload" command.
+	uploadCommand := &cli.Command{
+		Name:      "upload",
+		Usage:     "Install the public key of a key pair as a GitHub deploy key",
+		Args:      true,
+		ArgsUsage: " [id]",
+		Flags: []cli.Flag{
+			tokenFlag,
+			&cli.BoolFlag{
+				Name:  "write",
+				Usage: "Install the deploy key with write access instead of read-only",
+			},
+		},
+		Action: CliUploadKey,
+	}
+
+	// "revoke" command.
+	revokeCommand := &cli.Command{
+		Name:      "revoke",
+		Usage:     "Delete the deploy key previously installed with \"upload\"",
+		Args:      true,
+		ArgsUsage: " [id]",
+		Flags:     []cli.Flag{tokenFlag},
+		Action:    CliRevokeKey,
 	}
 
 	// "pull" command.
+	pullCommand := &cli.Command{
+		Name:      "pull",
+		Usage:     "Pull updates for the working tree associated with the given key pair",
+		Args:      true,
+		ArgsUsage: " [id]",
+		Flags: []cli.Flag{
+			&cli.BoolFlag{
+				Name:  "rebase",
+				Usage: "Rebase local commits on top of the pulled changes (not supported)",
+			},
+			&cli.BoolFlag{
+				Name:  "ff-only",
+				Value: true,
+				Usage: "Only pull if the merge can be resolved as a fast-forward",
+			},
+			&cli.BoolFlag{
+				Name:  "force",
+				Usage: "Pull even if the working tree has uncommitted changes",
+			},
+			passphraseFlag,
+		},
+		Action: CliPullRepository,
+	}
+
+	// "gitssh" command. It is hidden since it is meant to be invoked by git
+	// itself via GIT_SSH_COMMAND, not directly by users.
+	gitsshCommand := &cli.Command{
+		Name:            "gitssh",
+		Usage:           "Run ssh using the private key of a key pair (for GIT_SSH_COMMAND)",
+		Args:            true,
+		ArgsUsage:       " [id] [-- ssh-args...]",
+		Hidden:          true,
+		SkipFlagParsing: true,
+		Action:          CliGitSSH,
+	}
 
-	app.Commands = []*cli.Command{genCommand, getCommand, cloneCommand}
+	app.Commands = []*cli.Command{genCommand, getCommand, cloneCommand, uploadCommand, revokeCommand, pullCommand, gitsshCommand}
 
 	// Run the appliction.
 	if err := app.Run(os.Args); err != nil {